@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// AppAuth configures authentication as a GitHub App installation instead
+// of a personal access token.
+type AppAuth struct {
+	AppID          int64
+	InstallationID int64
+	PrivateKeyPEM  []byte
+	// BaseURL is the REST API base used to mint installation tokens,
+	// defaulting to defaultAppBaseURL for github.com.
+	BaseURL string
+}
+
+const (
+	defaultAppBaseURL = "https://api.github.com"
+	// jwtLifetime stays under GitHub's 10-minute cap on App JWTs.
+	jwtLifetime        = 9 * time.Minute
+	tokenRefreshMargin = time.Minute
+)
+
+// NewGithubClientFromApp builds a GithubClient authenticated as a GitHub
+// App installation: it mints a JWT from auth, exchanges it for an
+// installation access token, and wraps outgoing requests in a transport
+// that refreshes the token shortly before it expires. The existing PAT
+// path (NewGithubClient, InitClient) is unaffected.
+func NewGithubClientFromApp(ctx context.Context, owner, repo string, auth AppAuth, opts ClientOptions) (*GithubClient, error) {
+	src, err := newInstallationTokenSource(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.HTTPClient = &http.Client{Transport: &installationTransport{src: src}}
+
+	return NewGithubClient(ctx, owner, repo, opts)
+}
+
+// installationTokenSource mints and caches a GitHub App installation
+// access token, refreshing it shortly before it expires.
+type installationTokenSource struct {
+	auth AppAuth
+	key  *rsa.PrivateKey
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func newInstallationTokenSource(auth AppAuth) (*installationTokenSource, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(auth.PrivateKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %s", err)
+	}
+
+	if auth.BaseURL == "" {
+		auth.BaseURL = defaultAppBaseURL
+	}
+
+	return &installationTokenSource{auth: auth, key: key}, nil
+}
+
+// Token returns a valid installation access token, minting a new one if
+// the cached token is missing or close to expiry.
+func (s *installationTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Until(s.expiresAt) > tokenRefreshMargin {
+		return s.token, nil
+	}
+
+	jwtToken, err := s.signJWT()
+	if err != nil {
+		return "", err
+	}
+
+	token, expiresAt, err := s.exchangeForInstallationToken(ctx, jwtToken)
+	if err != nil {
+		return "", err
+	}
+
+	s.token, s.expiresAt = token, expiresAt
+
+	return token, nil
+}
+
+func (s *installationTokenSource) signJWT() (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)), // allow for clock drift
+		ExpiresAt: jwt.NewNumericDate(now.Add(jwtLifetime)),
+		Issuer:    fmt.Sprintf("%d", s.auth.AppID),
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(s.key)
+}
+
+func (s *installationTokenSource) exchangeForInstallationToken(ctx context.Context, jwtToken string) (string, time.Time, error) {
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", s.auth.BaseURL, s.auth.InstallationID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwtToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to request installation token: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return "", time.Time{}, fmt.Errorf("failed to request installation token: %s: %s", resp.Status, body)
+	}
+
+	var out struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to decode installation token response: %s", err)
+	}
+
+	return out.Token, out.ExpiresAt, nil
+}
+
+// installationTransport injects a fresh installation access token into
+// every outgoing request's Authorization header, minting or refreshing it
+// as needed via src.
+type installationTransport struct {
+	src  *installationTokenSource
+	next http.RoundTripper
+}
+
+func (t *installationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.src.Token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return next.RoundTrip(req)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// generateTestAppKey returns a freshly generated RSA private key as a
+// PKCS#1 PEM block, for use as AppAuth.PrivateKeyPEM.
+func generateTestAppKey(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %s", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+}
+
+// newTestTokenServer serves the installation-token exchange endpoint,
+// minting a new token (and counting requests) on every call.
+func newTestTokenServer(t *testing.T, expiresIn time.Duration) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			t.Errorf("token exchange request missing a Bearer JWT: %s", r.Header.Get("Authorization"))
+		}
+
+		n := atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":      fmt.Sprintf("installation-token-%d", n),
+			"expires_at": time.Now().Add(expiresIn),
+		})
+	}))
+
+	return server, &requests
+}
+
+func TestInstallationTokenSourceCachesUntilNearExpiry(t *testing.T) {
+	tokenServer, requests := newTestTokenServer(t, jwtLifetime)
+	defer tokenServer.Close()
+
+	src, err := newInstallationTokenSource(AppAuth{AppID: 1, InstallationID: 99, PrivateKeyPEM: generateTestAppKey(t), BaseURL: tokenServer.URL})
+	if err != nil {
+		t.Fatalf("newInstallationTokenSource: %s", err)
+	}
+
+	first, err := src.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token: %s", err)
+	}
+
+	second, err := src.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token: %s", err)
+	}
+
+	if first != second {
+		t.Errorf("Token minted a new token (%q then %q) while the cached one was still fresh", first, second)
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("token server received %d requests, want 1", got)
+	}
+}
+
+func TestInstallationTokenSourceRefreshesNearExpiry(t *testing.T) {
+	tokenServer, requests := newTestTokenServer(t, tokenRefreshMargin/2)
+	defer tokenServer.Close()
+
+	src, err := newInstallationTokenSource(AppAuth{AppID: 1, InstallationID: 99, PrivateKeyPEM: generateTestAppKey(t), BaseURL: tokenServer.URL})
+	if err != nil {
+		t.Fatalf("newInstallationTokenSource: %s", err)
+	}
+
+	first, err := src.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token: %s", err)
+	}
+
+	second, err := src.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token: %s", err)
+	}
+
+	if first == second {
+		t.Error("Token reused a token that was within its refresh margin")
+	}
+	if got := atomic.LoadInt32(requests); got != 2 {
+		t.Errorf("token server received %d requests, want 2", got)
+	}
+}
+
+// recordingTransport records the Authorization header it observed and
+// replies with a canned response.
+type recordingTransport struct {
+	gotAuth string
+	body    string
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.gotAuth = req.Header.Get("Authorization")
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", "application/json")
+	rec.WriteString(t.body)
+	rec.Code = http.StatusOK
+	return rec.Result(), nil
+}
+
+func TestInstallationTransportInjectsBearerToken(t *testing.T) {
+	tokenServer, _ := newTestTokenServer(t, jwtLifetime)
+	defer tokenServer.Close()
+
+	src, err := newInstallationTokenSource(AppAuth{AppID: 1, InstallationID: 99, PrivateKeyPEM: generateTestAppKey(t), BaseURL: tokenServer.URL})
+	if err != nil {
+		t.Fatalf("newInstallationTokenSource: %s", err)
+	}
+
+	next := &recordingTransport{body: `{"data":{}}`}
+	transport := &installationTransport{src: src, next: next}
+
+	req, err := http.NewRequest(http.MethodPost, "https://api.github.com/graphql", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %s", err)
+	}
+
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip: %s", err)
+	}
+
+	wantPrefix := "Bearer installation-token-"
+	if !strings.HasPrefix(next.gotAuth, wantPrefix) {
+		t.Errorf("Authorization header = %q, want prefix %q", next.gotAuth, wantPrefix)
+	}
+}
+
+func TestNewGithubClientFromAppAuthenticatesGraphQLRequests(t *testing.T) {
+	tokenServer, _ := newTestTokenServer(t, jwtLifetime)
+	defer tokenServer.Close()
+
+	var gotAuth string
+	graphqlServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"deleteRefInput":{"clientMutationId":"done"}}}`)
+	}))
+	defer graphqlServer.Close()
+
+	auth := AppAuth{AppID: 1, InstallationID: 99, PrivateKeyPEM: generateTestAppKey(t), BaseURL: tokenServer.URL}
+	client, err := NewGithubClientFromApp(context.Background(), "acme", "widgets", auth, ClientOptions{BaseURL: graphqlServer.URL})
+	if err != nil {
+		t.Fatalf("NewGithubClientFromApp: %s", err)
+	}
+
+	if _, err := client.DeleteBranch(context.Background(), "some-ref-id"); err != nil {
+		t.Fatalf("DeleteBranch: %s", err)
+	}
+
+	if !strings.HasPrefix(gotAuth, "Bearer installation-token-") {
+		t.Errorf("GraphQL request Authorization header = %q, want an installation token", gotAuth)
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+const (
+	// branchPageSize is how many refs are requested per GraphQL page.
+	branchPageSize = 50
+	// rateLimitThreshold is the remaining-points floor below which
+	// BranchIterator.Next pauses until the rate limit resets.
+	rateLimitThreshold = 50
+)
+
+// RateLimit is a snapshot of the GraphQL rate limit as of the last page
+// fetched by a BranchIterator.
+type RateLimit struct {
+	Remaining int
+	ResetAt   time.Time
+}
+
+// BranchIterator walks the branches matching a glob pattern one page at a
+// time, following GitHub's `pageInfo { hasNextPage endCursor }` cursor so
+// callers aren't capped at a single `first: N` page. It also watches the
+// GraphQL rate limit and, once remaining points drop below
+// rateLimitThreshold, pauses until ResetAt before fetching the next page.
+type BranchIterator struct {
+	client  *GithubClient
+	glob    *Glob
+	prefix  string
+	cursor  githubv4.String
+	started bool
+	done    bool
+
+	// RateLimit reflects the response to the most recently fetched page.
+	RateLimit RateLimit
+}
+
+// GetBranches returns an iterator over the branches matching pattern, a
+// glob where `*` matches within a path segment and `**` matches across
+// `/`. Call Next repeatedly to walk all matching branches regardless of
+// how many there are.
+func (github *GithubClient) GetBranches(pattern string) (*BranchIterator, error) {
+	glob, err := CompileGlob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid branch pattern %q: %s", pattern, err)
+	}
+
+	return &BranchIterator{client: github, glob: glob, prefix: staticPrefix(pattern)}, nil
+}
+
+// Next fetches the next page of matching branches. ok is false once every
+// page has been consumed, at which point branches is nil and err is nil.
+func (it *BranchIterator) Next(ctx context.Context) (branches []Branch, ok bool, err error) {
+	if it.done {
+		return nil, false, nil
+	}
+
+	if err := it.waitForRateLimit(ctx); err != nil {
+		return nil, false, err
+	}
+
+	var query struct {
+		RateLimit struct {
+			Remaining githubv4.Int
+			ResetAt   githubv4.DateTime
+		}
+		RepositoryOwner struct {
+			Repository struct {
+				Refs struct {
+					Edges []struct {
+						Node struct {
+							Name githubv4.String
+							Id   githubv4.ID
+						}
+					}
+					PageInfo struct {
+						HasNextPage githubv4.Boolean
+						EndCursor   githubv4.String
+					}
+				} `graphql:"refs(refPrefix: $prefix, first: $num, after: $cursor)"`
+			} `graphql:"repository(name: $repo)"`
+		} `graphql:"repositoryOwner(login: $owner)"`
+	}
+
+	var cursor *githubv4.String
+	if it.started {
+		cursor = &it.cursor
+	}
+
+	vars := map[string]interface{}{
+		"owner":  githubv4.String(it.client.Owner),
+		"repo":   githubv4.String(it.client.Repository),
+		"prefix": githubv4.String("refs/heads/" + it.prefix),
+		"num":    githubv4.Int(branchPageSize),
+		"cursor": cursor,
+	}
+
+	if err = it.client.Client.Query(ctx, &query, vars); err != nil {
+		return nil, false, fmt.Errorf("failed to query branches: %s", err)
+	}
+
+	it.started = true
+	it.RateLimit = RateLimit{
+		Remaining: int(query.RateLimit.Remaining),
+		ResetAt:   query.RateLimit.ResetAt.Time,
+	}
+
+	for _, br := range query.RepositoryOwner.Repository.Refs.Edges {
+		name := string(br.Node.Name)
+		if !it.glob.Match(name) {
+			continue
+		}
+		branches = append(branches, Branch{Name: name, ID: br.Node.Id})
+	}
+
+	it.cursor = query.RepositoryOwner.Repository.Refs.PageInfo.EndCursor
+	it.done = !bool(query.RepositoryOwner.Repository.Refs.PageInfo.HasNextPage)
+
+	return branches, true, nil
+}
+
+// lastCommitAuthor returns the GitHub login of branch's tip commit author,
+// or "" if the commit has no linked GitHub account (e.g. authored with an
+// unrecognized email).
+func (github *GithubClient) lastCommitAuthor(ctx context.Context, branch Branch) (string, error) {
+	var query struct {
+		Node struct {
+			Ref struct {
+				Target struct {
+					Commit struct {
+						Author struct {
+							User struct {
+								Login githubv4.String
+							}
+						}
+					} `graphql:"... on Commit"`
+				}
+			} `graphql:"... on Ref"`
+		} `graphql:"node(id: $id)"`
+	}
+
+	vars := map[string]interface{}{
+		"id": branch.ID,
+	}
+
+	if err := github.Client.Query(ctx, &query, vars); err != nil {
+		return "", fmt.Errorf("failed to query commit author for %s: %s", branch.Name, err)
+	}
+
+	return string(query.Node.Ref.Target.Commit.Author.User.Login), nil
+}
+
+// waitForRateLimit blocks until the rate limit observed on the previous
+// page has recovered, if it had dropped below rateLimitThreshold.
+func (it *BranchIterator) waitForRateLimit(ctx context.Context) error {
+	if !it.started || it.RateLimit.Remaining >= rateLimitThreshold {
+		return nil
+	}
+
+	wait := time.Until(it.RateLimit.ResetAt)
+	if wait <= 0 {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// CollectBranches drains a BranchIterator for pattern, paging until either
+// every matching branch has been fetched or limit results have been
+// collected (limit <= 0 means unbounded). It's a convenience wrapper for
+// callers that want a plain slice instead of streaming pages themselves.
+func (github *GithubClient) CollectBranches(ctx context.Context, pattern string, limit int) ([]Branch, error) {
+	it, err := github.GetBranches(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []Branch
+	for {
+		page, ok, err := it.Next(ctx)
+		if err != nil {
+			return res, err
+		}
+
+		res = append(res, page...)
+		if !ok || (limit > 0 && len(res) >= limit) {
+			break
+		}
+	}
+
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+
+	return res, nil
+}
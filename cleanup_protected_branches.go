@@ -2,19 +2,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
-	"strings"
 
 	"github.com/shurcooL/githubv4"
 	"golang.org/x/oauth2"
 )
 
+// ErrNoBranchProtectionRule indicates GetBranchProtectionRuleID's query
+// succeeded but no rule's pattern matched the glob passed in - distinct
+// from a failure of the query itself.
+var ErrNoBranchProtectionRule = errors.New("no branch protection rule matches pattern")
+
 type Github interface {
-	GetBranches(prefix string, num int) ([]string, error)
-	DeleteBranch(refId githubv4.ID) (string, error)
-	AllowDeleteProtectedBranch(branchRuleID githubv4.ID, allow bool) (string, error)
-	GetBranchProtectionRuleID(prefix string) (githubv4.ID, error)
+	GetBranches(prefix string) (*BranchIterator, error)
+	DeleteBranch(ctx context.Context, refId githubv4.ID) (string, error)
+	AllowDeleteProtectedBranch(ctx context.Context, branchRuleID githubv4.ID, allow bool) (string, error)
+	GetBranchProtectionRuleID(ctx context.Context, prefix string) (githubv4.ID, error)
 }
 
 type GithubClient struct {
@@ -28,62 +34,87 @@ type Branch struct {
 	Name string
 }
 
-func InitClient() (*GithubClient, error) {
-	owner := os.Getenv("GITHUB_OWNER")
-	repo := os.Getenv("GITHUB_REPO")
-	src := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: os.Getenv("GITHUB_TOKEN")},
-	)
-	httpClient := oauth2.NewClient(context.Background(), src)
-
-	return &GithubClient{
-		Owner:      owner,
-		Repository: repo,
-		Client:     githubv4.NewClient(httpClient),
-	}, nil
+// ClientOptions configures NewGithubClient. HTTPClient, BaseURL, UserAgent
+// and RetryPolicy are all optional; zero values fall back to the defaults
+// used against github.com with a PAT.
+type ClientOptions struct {
+	// HTTPClient is the transport used to reach GitHub. If nil, a client
+	// authenticated with GITHUB_TOKEN via oauth2 is built.
+	HTTPClient *http.Client
+	// BaseURL points the client at a GitHub Enterprise GraphQL endpoint
+	// instead of github.com.
+	BaseURL string
+	// UserAgent is sent with every request. Defaults to "ggg-api".
+	UserAgent string
+	// RetryPolicy controls backoff on retryable errors. Defaults to
+	// DefaultRetryPolicy().
+	RetryPolicy RetryPolicy
 }
 
-func (github *GithubClient) GetBranches(prefix string, num int) ([]Branch, error) {
-	var query struct {
-		Organization struct {
-			Repository struct {
-				Refs struct {
-					Edges []struct {
-						Node struct {
-							Name githubv4.String
-							Id   githubv4.ID
-						}
-					}
-				} `graphql:"refs(refPrefix: $prefix, first: $num)"`
-			} `graphql:"repository(name: $repo)"`
-		} `graphql:"organization(login: $owner)"`
+// NewGithubClient builds a GithubClient for owner/repo using opts. ctx is
+// used only to construct the default oauth2 transport when
+// opts.HTTPClient is nil; it is not retained.
+func NewGithubClient(ctx context.Context, owner, repo string, opts ClientOptions) (*GithubClient, error) {
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		src := oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: os.Getenv("GITHUB_TOKEN")},
+		)
+		httpClient = oauth2.NewClient(ctx, src)
 	}
 
-	vars := map[string]interface{}{
-		"owner":  githubv4.String(github.Owner),
-		"repo":   githubv4.String(github.Repository),
-		"prefix": githubv4.String("refs/heads/" + prefix),
-		"num":    githubv4.Int(num),
+	userAgent := opts.UserAgent
+	if userAgent == "" {
+		userAgent = "ggg-api"
 	}
 
-	err := github.Client.Query(context.Background(), &query, vars)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to query branches: %s", err)
+	retryPolicy := opts.RetryPolicy
+	if retryPolicy.MaxRetries == 0 && retryPolicy.BaseDelay == 0 {
+		retryPolicy = DefaultRetryPolicy()
 	}
 
-	res := make([]Branch, 0)
+	httpClient.Transport = newRetryTransport(&userAgentTransport{
+		next:      httpClient.Transport,
+		userAgent: userAgent,
+	}, retryPolicy)
 
-	for _, br := range query.Organization.Repository.Refs.Edges {
-		res = append(res, Branch{Name: string(br.Node.Name), ID: br.Node.Id})
+	var client *githubv4.Client
+	if opts.BaseURL != "" {
+		client = githubv4.NewEnterpriseClient(opts.BaseURL, httpClient)
+	} else {
+		client = githubv4.NewClient(httpClient)
 	}
 
-	return res, nil
+	return &GithubClient{
+		Owner:      owner,
+		Repository: repo,
+		Client:     client,
+	}, nil
+}
+
+// InitClient builds a GithubClient from the GITHUB_OWNER, GITHUB_REPO and
+// GITHUB_TOKEN environment variables, the historical entry point kept for
+// backward compatibility. New callers should prefer NewGithubClient.
+func InitClient() (*GithubClient, error) {
+	ctx := context.Background()
+	owner := os.Getenv("GITHUB_OWNER")
+	repo := os.Getenv("GITHUB_REPO")
+
+	return NewGithubClient(ctx, owner, repo, ClientOptions{})
 }
 
-func (github *GithubClient) GetBranchProtectionRuleID(prefix string) (githubv4.ID, error) {
+// GetBranchProtectionRuleID returns the ID of the branch protection rule
+// whose configured pattern matches pattern, treated as a glob (`*` within a
+// segment, `**` across `/`). This lets callers target rules like
+// `release/*` or `hotfix/**` without requiring an exact prefix match.
+func (github *GithubClient) GetBranchProtectionRuleID(ctx context.Context, pattern string) (githubv4.ID, error) {
+	glob, err := CompileGlob(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid branch pattern %q: %s", pattern, err)
+	}
+
 	var query struct {
-		Organization struct {
+		RepositoryOwner struct {
 			Repository struct {
 				BranchProtectionRules struct {
 					Edges []struct {
@@ -94,29 +125,29 @@ func (github *GithubClient) GetBranchProtectionRuleID(prefix string) (githubv4.I
 					}
 				} `graphql:"branchProtectionRules(first: 10)"`
 			} `graphql:"repository(name: $repo)"`
-		} `graphql:"organization(login: $owner)"`
+		} `graphql:"repositoryOwner(login: $owner)"`
 	}
 	vars := map[string]interface{}{
 		"owner": githubv4.String(github.Owner),
 		"repo":  githubv4.String(github.Repository),
 	}
 
-	err := github.Client.Query(context.Background(), &query, vars)
+	err = github.Client.Query(ctx, &query, vars)
 
 	if err != nil {
 		return "", fmt.Errorf("failed to query branch rules: %s", err)
 	}
 
-	for _, br := range query.Organization.Repository.BranchProtectionRules.Edges {
-		if strings.HasPrefix(string(br.Node.Pattern), prefix) {
+	for _, br := range query.RepositoryOwner.Repository.BranchProtectionRules.Edges {
+		if glob.Match(string(br.Node.Pattern)) {
 			return br.Node.Id, nil
 		}
 	}
 
-	return "", fmt.Errorf("could not find branch rule with prefix %s, check your Github settings", prefix)
+	return "", fmt.Errorf("%w: %s, check your Github settings", ErrNoBranchProtectionRule, pattern)
 }
 
-func (github *GithubClient) AllowDeleteProtectedBranch(branchRuleID githubv4.ID, allow githubv4.Boolean) (string, error) {
+func (github *GithubClient) AllowDeleteProtectedBranch(ctx context.Context, branchRuleID githubv4.ID, allow githubv4.Boolean) (string, error) {
 	var mutation struct {
 		UpdateBranchProtectionRule struct {
 			BranchProtectionRule struct {
@@ -129,7 +160,7 @@ func (github *GithubClient) AllowDeleteProtectedBranch(branchRuleID githubv4.ID,
 		AllowsDeletions:        &allow,
 	}
 
-	err := github.Client.Mutate(context.Background(), &mutation, input, nil)
+	err := github.Client.Mutate(ctx, &mutation, input, nil)
 
 	if err != nil {
 		return "", fmt.Errorf("failed to mutate branch protection rule: %s", err)
@@ -138,7 +169,7 @@ func (github *GithubClient) AllowDeleteProtectedBranch(branchRuleID githubv4.ID,
 	return fmt.Sprintf("protection rule updated, branch protection is now: %t", allow), nil
 }
 
-func (github *GithubClient) DeleteBranch(refId githubv4.ID) (string, error) {
+func (github *GithubClient) DeleteBranch(ctx context.Context, refId githubv4.ID) (string, error) {
 	var mutation struct {
 		DeleteRef struct {
 			ClientMutationId githubv4.ID
@@ -149,7 +180,7 @@ func (github *GithubClient) DeleteBranch(refId githubv4.ID) (string, error) {
 		RefID: refId,
 	}
 
-	err := github.Client.Mutate(context.Background(), &mutation, input, nil)
+	err := github.Client.Mutate(ctx, &mutation, input, nil)
 
 	if err != nil {
 		return "", fmt.Errorf("failed to mutate ref: %s", err)
@@ -159,18 +190,20 @@ func (github *GithubClient) DeleteBranch(refId githubv4.ID) (string, error) {
 }
 
 func main() {
+	ctx := context.Background()
+
 	client, err := InitClient()
 	if err != nil {
 		fmt.Printf("could not initialize client: %s", err)
 	}
 
-	branchProtectionRuleID, err := client.GetBranchProtectionRuleID("release/")
+	branchProtectionRuleID, err := client.GetBranchProtectionRuleID(ctx, "release/")
 
 	if err != nil {
 		fmt.Printf("could not find branch protection rule: %s", err)
 	}
 
-	status, err := client.AllowDeleteProtectedBranch(branchProtectionRuleID, githubv4.Boolean(false))
+	status, err := client.AllowDeleteProtectedBranch(ctx, branchProtectionRuleID, githubv4.Boolean(false))
 
 	if err != nil {
 		fmt.Printf("could not modify the branch protection rule: %s", err)
@@ -178,7 +211,7 @@ func main() {
 
 	fmt.Print(status)
 
-	// branch, err := client.GetBranches("release/", 5)
+	// branches, err := client.CollectBranches(ctx, "release/*", 5)
 	// if err != nil {
 	// 	fmt.Printf("could not list branches: %s", err)
 	// }
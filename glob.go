@@ -0,0 +1,87 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Glob is a compiled wildcard pattern for matching branch and rule names.
+// `*` matches any run of characters other than `/`, while `**` matches
+// across `/` separators, mirroring the semantics GitHub itself uses for
+// branch protection rule patterns.
+type Glob struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// CompileGlob compiles pattern into a Glob ready for matching. It never
+// fails today since the translation to regexp cannot produce an invalid
+// expression, but it returns an error to leave room for future validation
+// (e.g. rejecting empty patterns) without breaking callers.
+func CompileGlob(pattern string) (*Glob, error) {
+	re, err := regexp.Compile("^" + globToRegexp(pattern) + "$")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Glob{pattern: pattern, re: re}, nil
+}
+
+// Match reports whether name satisfies the compiled pattern.
+func (g *Glob) Match(name string) bool {
+	return g.re.MatchString(name)
+}
+
+// String returns the original, uncompiled pattern.
+func (g *Glob) String() string {
+	return g.pattern
+}
+
+// globToRegexp translates a glob pattern into the equivalent regexp source,
+// escaping everything that isn't one of the `*`/`**` wildcards. A `**`
+// bounded by `/` on both sides (e.g. the middle segment of
+// `feature/**/hotfix-*`) also matches zero intervening segments, the same
+// as gitignore-style `**` semantics, so it doesn't require at least one
+// path segment to exist between the surrounding literals.
+func globToRegexp(pattern string) string {
+	const (
+		doubleStarPlaceholder      = "\x00"
+		starPlaceholder            = "\x01"
+		slashDoubleStarPlaceholder = "\x02"
+	)
+
+	pattern = strings.ReplaceAll(pattern, "/**/", slashDoubleStarPlaceholder)
+
+	var b strings.Builder
+	runes := []rune(pattern)
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '*' {
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				b.WriteString(doubleStarPlaceholder)
+				i++
+				continue
+			}
+			b.WriteString(starPlaceholder)
+			continue
+		}
+		b.WriteRune(runes[i])
+	}
+
+	escaped := regexp.QuoteMeta(b.String())
+	escaped = strings.ReplaceAll(escaped, slashDoubleStarPlaceholder, `/(?:.*/)?`)
+	escaped = strings.ReplaceAll(escaped, doubleStarPlaceholder, ".*")
+	escaped = strings.ReplaceAll(escaped, starPlaceholder, "[^/]*")
+
+	return escaped
+}
+
+// staticPrefix returns the leading portion of pattern that contains no
+// wildcard characters, suitable for narrowing a GraphQL refPrefix filter
+// before the remaining glob is applied client-side.
+func staticPrefix(pattern string) string {
+	if idx := strings.IndexByte(pattern, '*'); idx != -1 {
+		return pattern[:idx]
+	}
+	return pattern
+}
@@ -0,0 +1,53 @@
+package main
+
+import "testing"
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"release/*", "release/2024-01", true},
+		{"release/*", "release/2024/01", false},
+		{"release/2024-*", "release/2024-12", true},
+		{"release/2024-*", "release/2023-12", false},
+		{"hotfix/**", "hotfix/a/b/c", true},
+		{"hotfix/**", "hotfix", false},
+		{"feature/**/hotfix-*", "feature/hotfix-1", true},
+		{"feature/**/hotfix-*", "feature/x/hotfix-1", true},
+		{"feature/**/hotfix-*", "feature/x/y/hotfix-1", true},
+		{"feature/**/hotfix-*", "feature/x/hotfix", false},
+		{"main", "main", true},
+		{"main", "mainline", false},
+	}
+
+	for _, tt := range tests {
+		glob, err := CompileGlob(tt.pattern)
+		if err != nil {
+			t.Fatalf("CompileGlob(%q) returned error: %s", tt.pattern, err)
+		}
+
+		if got := glob.Match(tt.name); got != tt.want {
+			t.Errorf("Glob(%q).Match(%q) = %t, want %t", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStaticPrefix(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"release/*", "release/"},
+		{"release/2024-*", "release/2024-"},
+		{"hotfix/**", "hotfix/"},
+		{"main", "main"},
+	}
+
+	for _, tt := range tests {
+		if got := staticPrefix(tt.pattern); got != tt.want {
+			t.Errorf("staticPrefix(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
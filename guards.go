@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// Guard failure reasons, analogous to the exit codes a pre-receive hook
+// would report.
+const (
+	GuardReasonOpenPR    = "open_pr"
+	GuardReasonNotMerged = "not_merged"
+	GuardReasonTooYoung  = "too_young"
+	GuardReasonDenied    = "denied"
+)
+
+// GuardError is returned when a branch fails one of DeleteBranchSafe's
+// pre-delete checks. Reason is a stable, machine-readable code; Message is
+// a human-readable detail suitable for logging.
+type GuardError struct {
+	Reason  string
+	Message string
+}
+
+func (e *GuardError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Reason, e.Message)
+}
+
+// GuardConfig configures the pre-receive-style safety checks
+// DeleteBranchSafe runs before deleting a ref.
+type GuardConfig struct {
+	// TargetBranch is the branch a candidate must be merged into, e.g.
+	// "main". Empty disables the merged check.
+	TargetBranch string
+	// MinAge is the minimum age a branch's tip commit must have reached.
+	// Zero disables the check.
+	MinAge time.Duration
+	// DenyList excludes branches matching any of these glob patterns.
+	DenyList []string
+	// CheckOpenPRs, if true, fails branches with an open pull request to
+	// or from them. False (the default) disables the check, consistent
+	// with TargetBranch and MinAge's zero-disables convention.
+	CheckOpenPRs bool
+	// Force skips every guard below and deletes unconditionally, mirroring
+	// a CLI --force flag.
+	Force bool
+}
+
+// DeleteBranchSafe runs the guards in cfg against branch and, only if they
+// all pass (or cfg.Force is set), deletes it exactly as DeleteBranch would.
+// The first failing guard aborts the deletion and is returned as a
+// *GuardError.
+func (github *GithubClient) DeleteBranchSafe(ctx context.Context, branch Branch, cfg GuardConfig) (string, error) {
+	if err := github.checkGuards(ctx, branch, cfg); err != nil {
+		return "", err
+	}
+
+	return github.DeleteBranch(ctx, branch.ID)
+}
+
+// checkGuards runs every configured guard against branch, returning the
+// first failing *GuardError, or nil if branch clears them all.
+func (github *GithubClient) checkGuards(ctx context.Context, branch Branch, cfg GuardConfig) error {
+	if cfg.Force {
+		return nil
+	}
+
+	if err := github.guardDenyList(branch, cfg.DenyList); err != nil {
+		return err
+	}
+
+	if cfg.CheckOpenPRs {
+		if err := github.guardOpenPRs(ctx, branch); err != nil {
+			return err
+		}
+	}
+
+	if cfg.TargetBranch != "" {
+		if err := github.guardMerged(ctx, branch, cfg.TargetBranch); err != nil {
+			return err
+		}
+	}
+
+	if cfg.MinAge > 0 {
+		if err := github.guardMinAge(ctx, branch, cfg.MinAge); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// guardDenyList fails if branch matches any of the given glob patterns.
+func (github *GithubClient) guardDenyList(branch Branch, patterns []string) error {
+	for _, pattern := range patterns {
+		glob, err := CompileGlob(pattern)
+		if err != nil {
+			continue
+		}
+		if glob.Match(branch.Name) {
+			return &GuardError{
+				Reason:  GuardReasonDenied,
+				Message: fmt.Sprintf("branch %s matches deny pattern %s", branch.Name, pattern),
+			}
+		}
+	}
+	return nil
+}
+
+// guardOpenPRs fails if branch has any open pull request targeting it or
+// originating from it. Callers opt in via GuardConfig.CheckOpenPRs.
+func (github *GithubClient) guardOpenPRs(ctx context.Context, branch Branch) error {
+	var query struct {
+		RepositoryOwner struct {
+			Repository struct {
+				AsHead struct {
+					TotalCount githubv4.Int
+				} `graphql:"asHead: pullRequests(states: OPEN, headRefName: $branch, first: 1)"`
+				AsBase struct {
+					TotalCount githubv4.Int
+				} `graphql:"asBase: pullRequests(states: OPEN, baseRefName: $branch, first: 1)"`
+			} `graphql:"repository(name: $repo)"`
+		} `graphql:"repositoryOwner(login: $owner)"`
+	}
+
+	vars := map[string]interface{}{
+		"owner":  githubv4.String(github.Owner),
+		"repo":   githubv4.String(github.Repository),
+		"branch": githubv4.String(branch.Name),
+	}
+
+	if err := github.Client.Query(ctx, &query, vars); err != nil {
+		return fmt.Errorf("failed to query open pull requests for %s: %s", branch.Name, err)
+	}
+
+	if query.RepositoryOwner.Repository.AsHead.TotalCount > 0 || query.RepositoryOwner.Repository.AsBase.TotalCount > 0 {
+		return &GuardError{
+			Reason:  GuardReasonOpenPR,
+			Message: fmt.Sprintf("branch %s has an open pull request", branch.Name),
+		}
+	}
+
+	return nil
+}
+
+// guardMerged fails unless branch's tip commit is associated with a merged
+// pull request whose base was target.
+func (github *GithubClient) guardMerged(ctx context.Context, branch Branch, target string) error {
+	var query struct {
+		Node struct {
+			Ref struct {
+				Target struct {
+					Commit struct {
+						AssociatedPullRequests struct {
+							Edges []struct {
+								Node struct {
+									MergedAt    githubv4.DateTime
+									BaseRefName githubv4.String
+								}
+							}
+						} `graphql:"associatedPullRequests(first: 5)"`
+					} `graphql:"... on Commit"`
+				}
+			} `graphql:"... on Ref"`
+		} `graphql:"node(id: $id)"`
+	}
+
+	vars := map[string]interface{}{
+		"id": branch.ID,
+	}
+
+	if err := github.Client.Query(ctx, &query, vars); err != nil {
+		return fmt.Errorf("failed to check merge status for %s: %s", branch.Name, err)
+	}
+
+	for _, edge := range query.Node.Ref.Target.Commit.AssociatedPullRequests.Edges {
+		if !edge.Node.MergedAt.Time.IsZero() && string(edge.Node.BaseRefName) == target {
+			return nil
+		}
+	}
+
+	return &GuardError{
+		Reason:  GuardReasonNotMerged,
+		Message: fmt.Sprintf("branch %s is not merged into %s", branch.Name, target),
+	}
+}
+
+// guardMinAge fails if branch's tip commit is younger than minAge.
+func (github *GithubClient) guardMinAge(ctx context.Context, branch Branch, minAge time.Duration) error {
+	var query struct {
+		Node struct {
+			Ref struct {
+				Target struct {
+					Commit struct {
+						CommittedDate githubv4.DateTime
+					} `graphql:"... on Commit"`
+				}
+			} `graphql:"... on Ref"`
+		} `graphql:"node(id: $id)"`
+	}
+
+	vars := map[string]interface{}{
+		"id": branch.ID,
+	}
+
+	if err := github.Client.Query(ctx, &query, vars); err != nil {
+		return fmt.Errorf("failed to check commit age for %s: %s", branch.Name, err)
+	}
+
+	age := time.Since(query.Node.Ref.Target.Commit.CommittedDate.Time)
+	if age < minAge {
+		return &GuardError{
+			Reason:  GuardReasonTooYoung,
+			Message: fmt.Sprintf("branch %s's last commit is %s old, younger than the %s minimum", branch.Name, age.Round(time.Minute), minAge),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeleteBranchSafeForceBypassesGuards(t *testing.T) {
+	client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+		if !strings.Contains(query, "deleteRefInput") {
+			return "", fmt.Errorf("unexpected query, guards should be skipped under Force: %s", query)
+		}
+		return `{"deleteRefInput":{"clientMutationId":"done"}}`, nil
+	})
+
+	branch := Branch{ID: "b1", Name: "tmp/anything"}
+	cfg := GuardConfig{DenyList: []string{"tmp/*"}, CheckOpenPRs: true, Force: true}
+
+	if _, err := client.DeleteBranchSafe(context.Background(), branch, cfg); err != nil {
+		t.Fatalf("DeleteBranchSafe with Force returned error: %s", err)
+	}
+}
+
+func TestDeleteBranchSafeDenyList(t *testing.T) {
+	client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+		return "", fmt.Errorf("unexpected query, deny list check is local: %s", query)
+	})
+
+	branch := Branch{ID: "b1", Name: "release/1.0"}
+	_, err := client.DeleteBranchSafe(context.Background(), branch, GuardConfig{DenyList: []string{"release/*"}})
+
+	var guardErr *GuardError
+	if !errors.As(err, &guardErr) || guardErr.Reason != GuardReasonDenied {
+		t.Fatalf("DeleteBranchSafe error = %v, want a GuardError with reason %s", err, GuardReasonDenied)
+	}
+}
+
+func TestDeleteBranchSafeOpenPR(t *testing.T) {
+	client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+		if !strings.Contains(query, "pullRequests") {
+			return "", fmt.Errorf("unexpected query: %s", query)
+		}
+		return `{"repositoryOwner":{"repository":{"asHead":{"totalCount":1},"asBase":{"totalCount":0}}}}`, nil
+	})
+
+	branch := Branch{ID: "b1", Name: "feature/x"}
+	_, err := client.DeleteBranchSafe(context.Background(), branch, GuardConfig{CheckOpenPRs: true})
+
+	var guardErr *GuardError
+	if !errors.As(err, &guardErr) || guardErr.Reason != GuardReasonOpenPR {
+		t.Fatalf("DeleteBranchSafe error = %v, want a GuardError with reason %s", err, GuardReasonOpenPR)
+	}
+}
+
+func TestDeleteBranchSafeNotMerged(t *testing.T) {
+	client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+		if !strings.Contains(query, "associatedPullRequests") {
+			return "", fmt.Errorf("unexpected query: %s", query)
+		}
+		return `{"node":{"target":{"associatedPullRequests":{"edges":[]}}}}`, nil
+	})
+
+	branch := Branch{ID: "b1", Name: "feature/x"}
+	_, err := client.DeleteBranchSafe(context.Background(), branch, GuardConfig{TargetBranch: "main"})
+
+	var guardErr *GuardError
+	if !errors.As(err, &guardErr) || guardErr.Reason != GuardReasonNotMerged {
+		t.Fatalf("DeleteBranchSafe error = %v, want a GuardError with reason %s", err, GuardReasonNotMerged)
+	}
+}
+
+func TestDeleteBranchSafeTooYoung(t *testing.T) {
+	client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+		if !strings.Contains(query, "committedDate") {
+			return "", fmt.Errorf("unexpected query: %s", query)
+		}
+		return fmt.Sprintf(`{"node":{"target":{"committedDate":%q}}}`, time.Now().Format(time.RFC3339)), nil
+	})
+
+	branch := Branch{ID: "b1", Name: "feature/x"}
+	_, err := client.DeleteBranchSafe(context.Background(), branch, GuardConfig{MinAge: 24 * time.Hour})
+
+	var guardErr *GuardError
+	if !errors.As(err, &guardErr) || guardErr.Reason != GuardReasonTooYoung {
+		t.Fatalf("DeleteBranchSafe error = %v, want a GuardError with reason %s", err, GuardReasonTooYoung)
+	}
+}
+
+func TestDeleteBranchSafeAllGuardsPass(t *testing.T) {
+	client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+		switch {
+		case strings.Contains(query, "associatedPullRequests"):
+			return `{"node":{"target":{"associatedPullRequests":{"edges":[{"node":{"mergedAt":"2024-01-01T00:00:00Z","baseRefName":"main"}}]}}}}`, nil
+		case strings.Contains(query, "pullRequests"):
+			return `{"repositoryOwner":{"repository":{"asHead":{"totalCount":0},"asBase":{"totalCount":0}}}}`, nil
+		case strings.Contains(query, "committedDate"):
+			return fmt.Sprintf(`{"node":{"target":{"committedDate":%q}}}`, time.Now().Add(-48*time.Hour).Format(time.RFC3339)), nil
+		case strings.Contains(query, "deleteRefInput"):
+			return `{"deleteRefInput":{"clientMutationId":"done"}}`, nil
+		}
+		return "", fmt.Errorf("unexpected query: %s", query)
+	})
+
+	branch := Branch{ID: "b1", Name: "feature/x"}
+	cfg := GuardConfig{
+		TargetBranch: "main",
+		MinAge:       24 * time.Hour,
+		CheckOpenPRs: true,
+		DenyList:     []string{"wip/*"},
+	}
+
+	if _, err := client.DeleteBranchSafe(context.Background(), branch, cfg); err != nil {
+		t.Fatalf("DeleteBranchSafe returned error for a branch that clears every guard: %s", err)
+	}
+}
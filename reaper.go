@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// ReaperConfig describes a single stale-branch cleanup run.
+type ReaperConfig struct {
+	// Pattern selects candidate branches, e.g. "release/*" or "tmp/**".
+	Pattern string
+	// MaxCandidates bounds how many matching branches are fetched before
+	// filtering; 0 uses a sensible default.
+	MaxCandidates int
+	// MinAge, if non-zero, excludes branches whose tip commit is younger
+	// than this. Enforced as a DeleteBranchSafe guard in deleteAll.
+	MinAge time.Duration
+	// RequireMerged, if true, only reaps branches already merged into
+	// MergedInto. Enforced as a DeleteBranchSafe guard in deleteAll.
+	RequireMerged bool
+	MergedInto    string
+	// SkipIfOpenPR excludes branches that have an open PR to or from
+	// them. Enforced as a DeleteBranchSafe guard in deleteAll.
+	SkipIfOpenPR bool
+	// AllowAuthors, if non-empty, restricts reaping to branches whose last
+	// commit author login is in the list.
+	AllowAuthors []string
+	// DenyAuthors excludes branches whose last commit author login is in
+	// the list, regardless of AllowAuthors.
+	DenyAuthors []string
+	// Concurrency bounds how many branches are deleted in parallel; 0
+	// uses a sensible default.
+	Concurrency int
+	// DryRun, if true, reports what would be deleted without mutating
+	// anything.
+	DryRun bool
+}
+
+const (
+	defaultReaperCandidates  = 100
+	defaultReaperConcurrency = 4
+)
+
+// ReapAction describes what the Reaper did (or would do) to a branch.
+type ReapAction string
+
+const (
+	ActionDeleted     ReapAction = "deleted"
+	ActionWouldDelete ReapAction = "would-delete"
+	ActionSkipped     ReapAction = "skipped"
+	ActionFailed      ReapAction = "failed"
+)
+
+// ReapResult is the outcome for a single candidate branch.
+type ReapResult struct {
+	Branch string     `json:"branch"`
+	Action ReapAction `json:"action"`
+	Reason string     `json:"reason,omitempty"`
+}
+
+// ReapReport is the JSON-serializable summary of a Reaper run.
+type ReapReport struct {
+	Pattern string       `json:"pattern"`
+	DryRun  bool         `json:"dry_run"`
+	Results []ReapResult `json:"results"`
+}
+
+// Reaper finds and deletes stale branches matching a pattern, temporarily
+// lifting branch protection's AllowsDeletions where needed and restoring
+// it afterwards.
+type Reaper struct {
+	Client *GithubClient
+	Config ReaperConfig
+}
+
+// NewReaper builds a Reaper that operates against client using cfg.
+func NewReaper(client *GithubClient, cfg ReaperConfig) *Reaper {
+	return &Reaper{Client: client, Config: cfg}
+}
+
+// Run lists branches matching the configured pattern, filters them down to
+// reap candidates, and - unless DryRun is set - deletes them, restoring the
+// matching branch protection rule's AllowsDeletions setting even if a
+// deletion fails partway through. Once the candidates have been listed it
+// returns a ReapReport describing what happened, alongside the first error
+// encountered (if any); if listing them fails, the report is nil.
+func (r *Reaper) Run(ctx context.Context) (*ReapReport, error) {
+	maxCandidates := r.Config.MaxCandidates
+	if maxCandidates == 0 {
+		maxCandidates = defaultReaperCandidates
+	}
+
+	branches, err := r.Client.CollectBranches(ctx, r.Config.Pattern, maxCandidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reap candidates: %s", err)
+	}
+
+	candidates, results, err := r.filter(ctx, branches)
+	report := &ReapReport{Pattern: r.Config.Pattern, DryRun: r.Config.DryRun, Results: results}
+	if err != nil {
+		return report, err
+	}
+
+	if r.Config.DryRun {
+		cfg := r.guardConfig()
+		for _, b := range candidates {
+			if err := r.Client.checkGuards(ctx, b, cfg); err != nil {
+				var guardErr *GuardError
+				if !errors.As(err, &guardErr) {
+					return report, err
+				}
+				report.Results = append(report.Results, ReapResult{Branch: b.Name, Action: ActionSkipped, Reason: guardErr.Message})
+				continue
+			}
+			report.Results = append(report.Results, ReapResult{Branch: b.Name, Action: ActionWouldDelete})
+		}
+		return report, nil
+	}
+
+	if len(candidates) == 0 {
+		return report, nil
+	}
+
+	restore, err := r.allowDeletions(ctx)
+	if restore != nil {
+		defer restore(ctx)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	deleteResults, err := r.deleteAll(ctx, candidates)
+	report.Results = append(report.Results, deleteResults...)
+
+	return report, err
+}
+
+// filter narrows branches down to reap candidates, recording a skipped
+// result for everything excluded by the author allow/deny lists. Age,
+// merge and open-PR based exclusions are left to the DeleteBranchSafe
+// guards deleteAll runs, so there's a single implementation of each check.
+func (r *Reaper) filter(ctx context.Context, branches []Branch) ([]Branch, []ReapResult, error) {
+	var (
+		candidates []Branch
+		results    []ReapResult
+	)
+
+	for _, b := range branches {
+		reason, err := r.exclusionReason(ctx, b)
+		if err != nil {
+			return candidates, results, fmt.Errorf("failed to check author of %s: %s", b.Name, err)
+		}
+		if reason != "" {
+			results = append(results, ReapResult{Branch: b.Name, Action: ActionSkipped, Reason: reason})
+			continue
+		}
+		candidates = append(candidates, b)
+	}
+
+	return candidates, results, nil
+}
+
+// exclusionReason returns a non-empty reason if b should not be reaped
+// given Config's author allow/deny lists, looking up b's actual tip-commit
+// author login when either list is set.
+func (r *Reaper) exclusionReason(ctx context.Context, b Branch) (string, error) {
+	if len(r.Config.AllowAuthors) == 0 && len(r.Config.DenyAuthors) == 0 {
+		return "", nil
+	}
+
+	author, err := r.Client.lastCommitAuthor(ctx, b)
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range r.Config.DenyAuthors {
+		if name == author {
+			return fmt.Sprintf("author %s is denied", author), nil
+		}
+	}
+
+	if len(r.Config.AllowAuthors) == 0 {
+		return "", nil
+	}
+
+	for _, name := range r.Config.AllowAuthors {
+		if name == author {
+			return "", nil
+		}
+	}
+
+	return fmt.Sprintf("author %s is not in the allow list", author), nil
+}
+
+// allowDeletions flips the matching branch protection rule's
+// AllowsDeletions to true and returns a function that restores it to false.
+// If no matching rule exists, or the rule already allows deletions, it
+// returns a nil restore func and a nil error and leaves the rule untouched.
+func (r *Reaper) allowDeletions(ctx context.Context) (func(context.Context), error) {
+	ruleID, err := r.Client.GetBranchProtectionRuleID(ctx, r.Config.Pattern)
+	if errors.Is(err, ErrNoBranchProtectionRule) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up branch protection rule: %s", err)
+	}
+
+	var query struct {
+		Node struct {
+			Rule struct {
+				AllowsDeletions githubv4.Boolean
+			} `graphql:"... on BranchProtectionRule"`
+		} `graphql:"node(id: $id)"`
+	}
+	if err := r.Client.Client.Query(ctx, &query, map[string]interface{}{"id": ruleID}); err != nil {
+		return nil, fmt.Errorf("failed to read branch protection rule: %s", err)
+	}
+	if query.Node.Rule.AllowsDeletions {
+		return nil, nil
+	}
+
+	if _, err := r.Client.AllowDeleteProtectedBranch(ctx, ruleID, githubv4.Boolean(true)); err != nil {
+		return nil, fmt.Errorf("failed to lift branch protection: %s", err)
+	}
+
+	restore := func(restoreCtx context.Context) {
+		if _, err := r.Client.AllowDeleteProtectedBranch(restoreCtx, ruleID, githubv4.Boolean(false)); err != nil {
+			fmt.Fprintf(os.Stderr, "reaper: failed to restore branch protection: %s\n", err)
+		}
+	}
+
+	return restore, nil
+}
+
+// guardConfig translates the age/merge/open-PR parts of Config into the
+// GuardConfig deleteAll runs through DeleteBranchSafe, so those checks
+// have one implementation shared with the standalone guard pass.
+func (r *Reaper) guardConfig() GuardConfig {
+	cfg := GuardConfig{
+		MinAge:       r.Config.MinAge,
+		CheckOpenPRs: r.Config.SkipIfOpenPR,
+	}
+	if r.Config.RequireMerged {
+		cfg.TargetBranch = r.Config.MergedInto
+	}
+	return cfg
+}
+
+// deleteAll runs candidates through DeleteBranchSafe with a bounded worker
+// pool, returning one result per branch. A branch that fails a guard is
+// recorded as skipped rather than failed, since that's expected filtering,
+// not an error.
+func (r *Reaper) deleteAll(ctx context.Context, candidates []Branch) ([]ReapResult, error) {
+	concurrency := r.Config.Concurrency
+	if concurrency == 0 {
+		concurrency = defaultReaperConcurrency
+	}
+
+	cfg := r.guardConfig()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		results  = make([]ReapResult, 0, len(candidates))
+		firstErr error
+		sem      = make(chan struct{}, concurrency)
+	)
+
+	for _, b := range candidates {
+		b := b
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result := ReapResult{Branch: b.Name, Action: ActionDeleted}
+
+			if _, err := r.Client.DeleteBranchSafe(ctx, b, cfg); err != nil {
+				var guardErr *GuardError
+				if errors.As(err, &guardErr) {
+					result.Action = ActionSkipped
+					result.Reason = guardErr.Message
+				} else {
+					result.Action = ActionFailed
+					result.Reason = err.Error()
+				}
+			}
+
+			mu.Lock()
+			results = append(results, result)
+			if result.Action == ActionFailed && firstErr == nil {
+				firstErr = fmt.Errorf("failed to delete branch %s: %s", b.Name, result.Reason)
+			}
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	return results, firstErr
+}
+
+// PrintReport writes report to stdout as indented JSON.
+func PrintReport(report *ReapReport) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
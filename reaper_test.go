@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// fakeGraphQLTransport answers githubv4 requests by decoding the query and
+// variables and delegating to handle, which returns the raw `data` JSON (or
+// an error to simulate a failed request).
+type fakeGraphQLTransport struct {
+	handle func(query string, vars map[string]interface{}) (string, error)
+}
+
+func (f *fakeGraphQLTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	raw, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, err
+	}
+
+	data, err := f.handle(payload.Query, payload.Variables)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(rec, `{"data":%s}`, data)
+	rec.Code = http.StatusOK
+	return rec.Result(), nil
+}
+
+// newFakeGithubClient builds a GithubClient whose GraphQL requests are
+// served by handle instead of a real network round trip.
+func newFakeGithubClient(handle func(query string, vars map[string]interface{}) (string, error)) *GithubClient {
+	httpClient := &http.Client{Transport: &fakeGraphQLTransport{handle: handle}}
+	return &GithubClient{
+		Owner:      "acme",
+		Repository: "widgets",
+		Client:     githubv4.NewClient(httpClient),
+	}
+}
+
+func TestReaperFilterAuthorAllowDenyLists(t *testing.T) {
+	authors := map[string]string{
+		"b1": "alice",
+		"b2": "bob",
+		"b3": "",
+	}
+
+	client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+		if !strings.Contains(query, "login") {
+			return "", fmt.Errorf("unexpected query: %s", query)
+		}
+		login := authors[vars["id"].(string)]
+		return fmt.Sprintf(`{"node":{"target":{"author":{"user":{"login":%q}}}}}`, login), nil
+	})
+
+	branches := []Branch{
+		{ID: "b1", Name: "feat/a"},
+		{ID: "b2", Name: "feat/b"},
+		{ID: "b3", Name: "feat/c"},
+	}
+
+	t.Run("allow list", func(t *testing.T) {
+		r := NewReaper(client, ReaperConfig{AllowAuthors: []string{"alice"}})
+		candidates, results, err := r.filter(context.Background(), branches)
+		if err != nil {
+			t.Fatalf("filter returned error: %s", err)
+		}
+		if len(candidates) != 1 || candidates[0].Name != "feat/a" {
+			t.Fatalf("candidates = %+v, want only feat/a", candidates)
+		}
+		if len(results) != 2 {
+			t.Fatalf("results = %+v, want 2 skipped", results)
+		}
+	})
+
+	t.Run("deny list", func(t *testing.T) {
+		r := NewReaper(client, ReaperConfig{DenyAuthors: []string{"alice"}})
+		candidates, results, err := r.filter(context.Background(), branches)
+		if err != nil {
+			t.Fatalf("filter returned error: %s", err)
+		}
+		if len(candidates) != 2 {
+			t.Fatalf("candidates = %+v, want feat/b and feat/c", candidates)
+		}
+		if len(results) != 1 || results[0].Branch != "feat/a" {
+			t.Fatalf("results = %+v, want feat/a skipped", results)
+		}
+	})
+
+	t.Run("no author config skips the lookup entirely", func(t *testing.T) {
+		client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+			return "", fmt.Errorf("unexpected query: %s", query)
+		})
+		r := NewReaper(client, ReaperConfig{})
+		candidates, results, err := r.filter(context.Background(), branches)
+		if err != nil {
+			t.Fatalf("filter returned error: %s", err)
+		}
+		if len(candidates) != len(branches) || len(results) != 0 {
+			t.Fatalf("candidates = %+v, results = %+v, want every branch kept with no queries", candidates, results)
+		}
+	})
+}
+
+func TestReaperDeleteAllRunsGuards(t *testing.T) {
+	now := time.Now()
+	committedDates := map[string]time.Time{
+		"stale": now.Add(-48 * time.Hour),
+		"young": now.Add(-time.Minute),
+	}
+
+	client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+		id, _ := vars["id"].(string)
+		switch {
+		case strings.Contains(query, "associatedPullRequests"):
+			return `{"node":{"target":{"associatedPullRequests":{"edges":[{"node":{"mergedAt":"2024-01-01T00:00:00Z","baseRefName":"main"}}]}}}}`, nil
+		case strings.Contains(query, "pullRequests"):
+			return `{"repositoryOwner":{"repository":{"asHead":{"totalCount":0},"asBase":{"totalCount":0}}}}`, nil
+		case strings.Contains(query, "committedDate"):
+			return fmt.Sprintf(`{"node":{"target":{"committedDate":%q}}}`, committedDates[id].Format(time.RFC3339)), nil
+		case strings.Contains(query, "deleteRefInput"):
+			return `{"deleteRefInput":{"clientMutationId":"done"}}`, nil
+		}
+		return "", fmt.Errorf("unexpected query: %s", query)
+	})
+
+	r := NewReaper(client, ReaperConfig{
+		RequireMerged: true,
+		MergedInto:    "main",
+		MinAge:        24 * time.Hour,
+		SkipIfOpenPR:  true,
+		Concurrency:   1,
+	})
+
+	candidates := []Branch{
+		{ID: "stale", Name: "tmp/stale"},
+		{ID: "young", Name: "tmp/young"},
+	}
+
+	results, err := r.deleteAll(context.Background(), candidates)
+	if err != nil {
+		t.Fatalf("deleteAll returned error: %s", err)
+	}
+
+	byBranch := map[string]ReapResult{}
+	for _, res := range results {
+		byBranch[res.Branch] = res
+	}
+
+	if got := byBranch["tmp/stale"]; got.Action != ActionDeleted {
+		t.Errorf("tmp/stale action = %s, want %s (result: %+v)", got.Action, ActionDeleted, got)
+	}
+	if got := byBranch["tmp/young"]; got.Action != ActionSkipped || got.Reason == "" {
+		t.Errorf("tmp/young action = %+v, want skipped with a reason", got)
+	}
+}
+
+func TestReaperAllowDeletionsDistinguishesNoRuleFromQueryError(t *testing.T) {
+	t.Run("no matching rule", func(t *testing.T) {
+		client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+			return `{"repositoryOwner":{"repository":{"branchProtectionRules":{"edges":[]}}}}`, nil
+		})
+		r := NewReaper(client, ReaperConfig{Pattern: "release/*"})
+
+		restore, err := r.allowDeletions(context.Background())
+		if err != nil {
+			t.Fatalf("allowDeletions returned error for a missing rule: %s", err)
+		}
+		if restore != nil {
+			t.Fatalf("allowDeletions returned a restore func with no rule to restore")
+		}
+	})
+
+	t.Run("query failure is surfaced", func(t *testing.T) {
+		client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+			return "", errors.New("connection reset")
+		})
+		r := NewReaper(client, ReaperConfig{Pattern: "release/*"})
+
+		if _, err := r.allowDeletions(context.Background()); err == nil {
+			t.Fatal("allowDeletions returned nil error for a failed query")
+		} else if errors.Is(err, ErrNoBranchProtectionRule) {
+			t.Fatalf("allowDeletions mistook a query failure for a missing rule: %s", err)
+		}
+	})
+}
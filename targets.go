@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// Target identifies a single repository to operate against.
+type Target struct {
+	Owner string
+	Repo  string
+}
+
+const defaultTargetRepoPageSize = 100
+
+// TargetsConfig describes the set of repositories a multi-repo operation
+// should fan out across: either an explicit list of owner/repo pairs, or a
+// single owner together with a glob matched against that owner's repo
+// names (e.g. Owner: "my-org", RepoPattern: "service-*").
+type TargetsConfig struct {
+	// Repos is an explicit list of owner/repo pairs to target. If set,
+	// Owner and RepoPattern are ignored.
+	Repos []Target
+	// Owner and RepoPattern, used together, target every repo owned by
+	// Owner (a user or an organization) whose name matches the glob
+	// RepoPattern.
+	Owner       string
+	RepoPattern string
+	// Concurrency bounds how many repos are operated on in parallel; 0
+	// uses a sensible default.
+	Concurrency int
+}
+
+// Resolve returns the concrete list of Targets described by cfg, expanding
+// Owner/RepoPattern into matching repo names via client if Repos wasn't
+// supplied directly.
+func (cfg TargetsConfig) Resolve(ctx context.Context, client *GithubClient) ([]Target, error) {
+	if len(cfg.Repos) > 0 {
+		return cfg.Repos, nil
+	}
+
+	if cfg.Owner == "" || cfg.RepoPattern == "" {
+		return nil, fmt.Errorf("targets: need either Repos or both Owner and RepoPattern")
+	}
+
+	glob, err := CompileGlob(cfg.RepoPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid repo pattern %q: %s", cfg.RepoPattern, err)
+	}
+
+	var (
+		targets []Target
+		cursor  *githubv4.String
+	)
+
+	for {
+		var query struct {
+			RepositoryOwner struct {
+				Repositories struct {
+					Nodes []struct {
+						Name githubv4.String
+					}
+					PageInfo struct {
+						HasNextPage githubv4.Boolean
+						EndCursor   githubv4.String
+					}
+				} `graphql:"repositories(first: $num, after: $cursor)"`
+			} `graphql:"repositoryOwner(login: $owner)"`
+		}
+
+		vars := map[string]interface{}{
+			"owner":  githubv4.String(cfg.Owner),
+			"num":    githubv4.Int(defaultTargetRepoPageSize),
+			"cursor": cursor,
+		}
+
+		if err := client.Client.Query(ctx, &query, vars); err != nil {
+			return nil, fmt.Errorf("failed to list repos for %s: %s", cfg.Owner, err)
+		}
+
+		for _, repo := range query.RepositoryOwner.Repositories.Nodes {
+			name := string(repo.Name)
+			if glob.Match(name) {
+				targets = append(targets, Target{Owner: cfg.Owner, Repo: name})
+			}
+		}
+
+		if !bool(query.RepositoryOwner.Repositories.PageInfo.HasNextPage) {
+			break
+		}
+		endCursor := query.RepositoryOwner.Repositories.PageInfo.EndCursor
+		cursor = &endCursor
+	}
+
+	return targets, nil
+}
+
+// TargetResult pairs a Target with the outcome of running an operation
+// against it.
+type TargetResult struct {
+	Target Target
+	Value  interface{}
+	Err    error
+}
+
+// FanOut resolves cfg against client, then runs fn once per matching
+// target - each against a GithubClient pointed at that target's
+// owner/repo, reusing client's transport and options - with up to
+// cfg.Concurrency targets in flight at once. It returns one TargetResult
+// per target, in no particular order, and aggregates no errors itself;
+// callers inspect each result's Err individually since a failure in one
+// repo shouldn't obscure successes in the others.
+func FanOut(ctx context.Context, client *GithubClient, cfg TargetsConfig, fn func(context.Context, *GithubClient) (interface{}, error)) ([]TargetResult, error) {
+	targets, err := cfg.Resolve(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := cfg.Concurrency
+	if concurrency == 0 {
+		concurrency = defaultReaperConcurrency
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results = make([]TargetResult, 0, len(targets))
+		sem     = make(chan struct{}, concurrency)
+	)
+
+	for _, target := range targets {
+		target := target
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			repoClient := &GithubClient{
+				Owner:      target.Owner,
+				Repository: target.Repo,
+				Client:     client.Client,
+			}
+
+			value, err := fn(ctx, repoClient)
+
+			mu.Lock()
+			results = append(results, TargetResult{Target: target, Value: value, Err: err})
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	return results, nil
+}
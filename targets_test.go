@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTargetsConfigResolveRepos(t *testing.T) {
+	client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+		return "", fmt.Errorf("unexpected query, explicit Repos shouldn't need one: %s", query)
+	})
+
+	cfg := TargetsConfig{Repos: []Target{{Owner: "acme", Repo: "a"}, {Owner: "acme", Repo: "b"}}}
+
+	targets, err := cfg.Resolve(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Resolve returned error: %s", err)
+	}
+	if len(targets) != 2 {
+		t.Fatalf("targets = %+v, want the 2 explicit repos", targets)
+	}
+}
+
+func TestTargetsConfigResolveOwnerPatternPages(t *testing.T) {
+	pages := [][]string{
+		{"service-a", "other-repo"},
+		{"service-b"},
+	}
+
+	client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+		if !strings.Contains(query, "repositories(first") {
+			return "", fmt.Errorf("unexpected query: %s", query)
+		}
+
+		page := 0
+		if vars["cursor"] != nil {
+			page = 1
+		}
+
+		nodes := ""
+		for _, name := range pages[page] {
+			if nodes != "" {
+				nodes += ","
+			}
+			nodes += fmt.Sprintf(`{"name":%q}`, name)
+		}
+
+		hasNext := page == 0
+		return fmt.Sprintf(`{"repositoryOwner":{"repositories":{"nodes":[%s],"pageInfo":{"hasNextPage":%t,"endCursor":"cursor-1"}}}}`, nodes, hasNext), nil
+	})
+
+	cfg := TargetsConfig{Owner: "acme", RepoPattern: "service-*"}
+
+	targets, err := cfg.Resolve(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Resolve returned error: %s", err)
+	}
+
+	var names []string
+	for _, target := range targets {
+		if target.Owner != "acme" {
+			t.Errorf("target owner = %q, want acme", target.Owner)
+		}
+		names = append(names, target.Repo)
+	}
+	sort.Strings(names)
+
+	want := []string{"service-a", "service-b"}
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] {
+		t.Fatalf("resolved repos = %v, want %v", names, want)
+	}
+}
+
+func TestFanOutRunsFnPerTargetWithItsOwnClient(t *testing.T) {
+	client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+		return "", fmt.Errorf("unexpected query: %s", query)
+	})
+
+	cfg := TargetsConfig{
+		Repos: []Target{
+			{Owner: "acme", Repo: "a"},
+			{Owner: "acme", Repo: "b"},
+		},
+	}
+
+	var mu sync.Mutex
+	var seen []string
+
+	fn := func(ctx context.Context, repoClient *GithubClient) (interface{}, error) {
+		mu.Lock()
+		seen = append(seen, repoClient.Owner+"/"+repoClient.Repository)
+		mu.Unlock()
+
+		if repoClient.Repository == "b" {
+			return nil, fmt.Errorf("boom")
+		}
+		return "ok", nil
+	}
+
+	results, err := FanOut(context.Background(), client, cfg, fn)
+	if err != nil {
+		t.Fatalf("FanOut returned error: %s", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %+v, want 2", results)
+	}
+
+	sort.Strings(seen)
+	if len(seen) != 2 || seen[0] != "acme/a" || seen[1] != "acme/b" {
+		t.Fatalf("fn ran against %v, want acme/a and acme/b", seen)
+	}
+
+	byRepo := map[string]TargetResult{}
+	for _, res := range results {
+		byRepo[res.Target.Repo] = res
+	}
+
+	if byRepo["a"].Err != nil || byRepo["a"].Value != "ok" {
+		t.Errorf("target a result = %+v, want Value ok and no error", byRepo["a"])
+	}
+	if byRepo["b"].Err == nil {
+		t.Errorf("target b result = %+v, want an error from fn, not aggregated away", byRepo["b"])
+	}
+}
+
+func TestFanOutBoundsConcurrency(t *testing.T) {
+	var targets []Target
+	for i := 0; i < 6; i++ {
+		targets = append(targets, Target{Owner: "acme", Repo: fmt.Sprintf("repo-%d", i)})
+	}
+
+	client := newFakeGithubClient(func(query string, vars map[string]interface{}) (string, error) {
+		return "", fmt.Errorf("unexpected query: %s", query)
+	})
+
+	var inFlight, maxInFlight int32
+	fn := func(ctx context.Context, repoClient *GithubClient) (interface{}, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return nil, nil
+	}
+
+	_, err := FanOut(context.Background(), client, TargetsConfig{Repos: targets, Concurrency: 2}, fn)
+	if err != nil {
+		t.Fatalf("FanOut returned error: %s", err)
+	}
+
+	if got := atomic.LoadInt32(&maxInFlight); got > 2 {
+		t.Errorf("max concurrent fn calls = %d, want at most 2", got)
+	}
+}
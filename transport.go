@@ -0,0 +1,158 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy controls how retryTransport backs off when GitHub responds
+// with a retryable error (5xx, or a 403/429 secondary rate limit).
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// DefaultRetryPolicy returns the backoff settings used when a
+// ClientOptions.RetryPolicy isn't supplied: a handful of exponentially
+// spaced retries capped at 30s apart.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// userAgentTransport sets a User-Agent header on every outgoing request
+// before delegating to next (http.DefaultTransport if next is nil).
+type userAgentTransport struct {
+	next      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.userAgent)
+
+	return next.RoundTrip(req)
+}
+
+// retryTransport wraps an http.RoundTripper and retries requests that fail
+// with a retryable status, honouring a Retry-After header when GitHub sends
+// one (e.g. on secondary rate limit responses).
+type retryTransport struct {
+	next   http.RoundTripper
+	policy RetryPolicy
+}
+
+func newRetryTransport(next http.RoundTripper, policy RetryPolicy) *retryTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &retryTransport{next: next, policy: policy}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		resp *http.Response
+		err  error
+	)
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if rerr := resetBody(req); rerr != nil {
+				return nil, rerr
+			}
+		}
+
+		resp, err = t.next.RoundTrip(req)
+		if err == nil && !isRetryableStatus(resp) {
+			return resp, nil
+		}
+
+		if attempt >= t.policy.MaxRetries {
+			return resp, err
+		}
+
+		delay := retryAfterDelay(resp)
+		if delay == 0 {
+			delay = backoffDelay(t.policy, attempt)
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// resetBody rewinds req.Body to its original contents via req.GetBody
+// before a retry, since the previous attempt's RoundTripper may have
+// already drained it (every githubv4 Query/Mutate call is a POST with a
+// JSON body). Requests with no body (GetBody nil) are left untouched.
+func resetBody(req *http.Request) error {
+	if req.GetBody == nil {
+		return nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+
+	req.Body = body
+	return nil
+}
+
+// isRetryableStatus reports whether resp warrants a retry: any 5xx, a 429,
+// or a 403 secondary rate limit response. A 403 is only treated as
+// retryable when it carries a Retry-After header, since GitHub uses the
+// same status for genuine auth failures and those should surface
+// immediately rather than be masked by a doomed retry loop.
+func isRetryableStatus(resp *http.Response) bool {
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode >= 500:
+		return true
+	case resp.StatusCode == http.StatusForbidden:
+		return resp.Header.Get("Retry-After") != ""
+	default:
+		return false
+	}
+}
+
+func retryAfterDelay(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	seconds := resp.Header.Get("Retry-After")
+	if seconds == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(seconds + "s")
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+func backoffDelay(policy RetryPolicy, attempt int) time.Duration {
+	delay := time.Duration(float64(policy.BaseDelay) * math.Pow(2, float64(attempt)))
+	if delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	return delay
+}
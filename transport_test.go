@@ -0,0 +1,120 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// countingTransport fails the first failCount requests with status (and
+// failHeader, if set), then succeeds, recording the body it observed on
+// every attempt.
+type countingTransport struct {
+	status     int
+	failCount  int
+	failHeader http.Header
+
+	attempts int
+	bodies   []string
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	t.bodies = append(t.bodies, string(body))
+	t.attempts++
+
+	status := http.StatusOK
+	rec := httptest.NewRecorder()
+	if t.attempts <= t.failCount {
+		status = t.status
+		for key, values := range t.failHeader {
+			for _, value := range values {
+				rec.Header().Add(key, value)
+			}
+		}
+	}
+	rec.Code = status
+
+	return rec.Result(), nil
+}
+
+func TestRetryTransportResendsBodyOnRetry(t *testing.T) {
+	fake := &countingTransport{status: http.StatusInternalServerError, failCount: 1}
+	rt := newRetryTransport(fake, RetryPolicy{MaxRetries: 2, BaseDelay: 0, MaxDelay: 0})
+
+	const payload = `{"query":"{ viewer { login } }"}`
+
+	req, err := http.NewRequest(http.MethodPost, "https://api.github.com/graphql", strings.NewReader(payload))
+	if err != nil {
+		t.Fatalf("NewRequest: %s", err)
+	}
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip returned error: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("final status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if fake.attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", fake.attempts)
+	}
+
+	for i, body := range fake.bodies {
+		if body != payload {
+			t.Errorf("attempt %d body = %q, want %q", i+1, body, payload)
+		}
+	}
+}
+
+func TestRetryTransportRetries403SecondaryRateLimitWithRetryAfter(t *testing.T) {
+	fake := &countingTransport{
+		status:     http.StatusForbidden,
+		failCount:  1,
+		failHeader: http.Header{"Retry-After": []string{"0"}},
+	}
+	rt := newRetryTransport(fake, RetryPolicy{MaxRetries: 2, BaseDelay: 0, MaxDelay: 0})
+
+	req, err := http.NewRequest(http.MethodPost, "https://api.github.com/graphql", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("NewRequest: %s", err)
+	}
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip returned error: %s", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("final status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if fake.attempts != 2 {
+		t.Fatalf("attempts = %d, want 2 (a retry after the 403 secondary rate limit)", fake.attempts)
+	}
+}
+
+func TestRetryTransportDoesNotRetry403WithoutRetryAfter(t *testing.T) {
+	fake := &countingTransport{status: http.StatusForbidden, failCount: 1}
+	rt := newRetryTransport(fake, RetryPolicy{MaxRetries: 2, BaseDelay: 0, MaxDelay: 0})
+
+	req, err := http.NewRequest(http.MethodPost, "https://api.github.com/graphql", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("NewRequest: %s", err)
+	}
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip returned error: %s", err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("final status = %d, want %d (a 403 with no Retry-After is a real auth failure, not retryable)", resp.StatusCode, http.StatusForbidden)
+	}
+	if fake.attempts != 1 {
+		t.Fatalf("attempts = %d, want 1 (no retry)", fake.attempts)
+	}
+}